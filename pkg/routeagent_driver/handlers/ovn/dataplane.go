@@ -0,0 +1,178 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovn
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/submariner/pkg/iptables"
+	"github.com/submariner-io/submariner/pkg/routeagent_driver/constants"
+	"k8s.io/klog/v2"
+	netutils "k8s.io/utils/net"
+)
+
+const (
+	// DataplaneBackendIPTables selects the legacy iptables.Interface-based dataplane.
+	DataplaneBackendIPTables = "iptables"
+	// DataplaneBackendNFTables selects the nftables-based dataplane.
+	DataplaneBackendNFTables = "nftables"
+	// DataplaneBackendAuto detects whether the host is running iptables-nft or legacy iptables
+	// and picks the matching backend.
+	DataplaneBackendAuto = "auto"
+)
+
+// Dataplane abstracts the chain and rule operations the OVN handler needs to steer Submariner
+// traffic and exempt remote subnets from masquerading, so the underlying packet filter
+// (iptables or nftables) can be swapped without touching the handler's control-plane logic.
+type Dataplane interface {
+	// EnsureSubmarinerChains creates the forwarding and postrouting chains/tables used to steer
+	// and no-masquerade Submariner traffic, if they don't already exist.
+	EnsureSubmarinerChains() error
+	// AddNoMasqueradeRule exempts the given remote subnet from masquerading.
+	AddNoMasqueradeRule(subnet string) error
+	// RemoveNoMasqueradeRule undoes AddNoMasqueradeRule for the given remote subnet.
+	RemoveNoMasqueradeRule(subnet string) error
+	// AddPodSNATRule SNATs traffic from podIP to subnet so it leaves via this node's IP instead of
+	// preserving the pod IP.
+	AddPodSNATRule(podIP, subnet string) error
+	// RemovePodSNATRule undoes AddPodSNATRule for the given podIP/subnet pair.
+	RemovePodSNATRule(podIP, subnet string) error
+	// Teardown removes every chain, rule and table this Dataplane created.
+	Teardown()
+	// Backend returns the resolved backend this Dataplane is using, i.e. DataplaneBackendIPTables
+	// or DataplaneBackendNFTables, even when the handler was configured with "auto".
+	Backend() string
+}
+
+// NewDataplane constructs the Dataplane implementation selected by backend ("iptables",
+// "nftables" or "auto"/""). ipt and ip6t back the iptables implementation; the nftables
+// implementation manages its own netlink-backed table.
+func NewDataplane(backend string, ipt, ip6t iptables.Interface) (Dataplane, error) {
+	switch backend {
+	case "", DataplaneBackendAuto:
+		backend = detectDataplaneBackend()
+	case DataplaneBackendIPTables, DataplaneBackendNFTables:
+	default:
+		return nil, errors.Errorf("unknown dataplane backend %q", backend)
+	}
+
+	if backend == DataplaneBackendNFTables {
+		return newNFTablesDataplane()
+	}
+
+	return &iptablesDataplane{ipt: ipt, ip6t: ip6t}, nil
+}
+
+// detectDataplaneBackend picks "nftables" when the host's iptables binary (itself a
+// update-alternatives symlink on Debian/RHEL) currently resolves to an nft-backed binary
+// (iptables-nft or xtables-nft-multi), and "iptables" otherwise, including when iptables isn't
+// managed by alternatives at all.
+func detectDataplaneBackend() string {
+	for _, path := range []string{"/usr/sbin/iptables", "/sbin/iptables"} {
+		target, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			continue
+		}
+
+		if strings.Contains(filepath.Base(target), "nft") {
+			klog.Infof("Detected nft-backed iptables via %q -> %q; using nftables dataplane", path, target)
+			return DataplaneBackendNFTables
+		}
+
+		klog.Infof("Detected legacy iptables via %q -> %q; using iptables dataplane", path, target)
+
+		return DataplaneBackendIPTables
+	}
+
+	return DataplaneBackendIPTables
+}
+
+// iptablesDataplane is the original Dataplane implementation, built directly on
+// iptables.Interface for both address families.
+type iptablesDataplane struct {
+	ipt  iptables.Interface
+	ip6t iptables.Interface
+}
+
+func (d *iptablesDataplane) Backend() string {
+	return DataplaneBackendIPTables
+}
+
+func (d *iptablesDataplane) EnsureSubmarinerChains() error {
+	// Chain creation for the iptables backend is handled by initIPtablesChains, which runs
+	// ahead of this call during Init; nothing further to do here.
+	return nil
+}
+
+func (d *iptablesDataplane) AddNoMasqueradeRule(subnet string) error {
+	return errors.Wrap(d.forFamily(subnet).AppendUnique(constants.NATTable, constants.SmPostRoutingChain,
+		"-d", subnet, "-j", "ACCEPT"), "error adding no-masquerade rule")
+}
+
+func (d *iptablesDataplane) RemoveNoMasqueradeRule(subnet string) error {
+	return errors.Wrap(d.forFamily(subnet).Delete(constants.NATTable, constants.SmPostRoutingChain,
+		"-d", subnet, "-j", "ACCEPT"), "error removing no-masquerade rule")
+}
+
+func (d *iptablesDataplane) AddPodSNATRule(podIP, subnet string) error {
+	return errors.Wrap(d.forFamily(subnet).AppendUnique(constants.NATTable, constants.SmPostRoutingChain,
+		"-s", podIP, "-d", subnet, "-j", "MASQUERADE"), "error adding pod SNAT rule")
+}
+
+func (d *iptablesDataplane) RemovePodSNATRule(podIP, subnet string) error {
+	return errors.Wrap(d.forFamily(subnet).Delete(constants.NATTable, constants.SmPostRoutingChain,
+		"-s", podIP, "-d", subnet, "-j", "MASQUERADE"), "error removing pod SNAT rule")
+}
+
+func (d *iptablesDataplane) forFamily(subnet string) iptables.Interface {
+	if netutils.IsIPv6CIDRString(subnet) {
+		return d.ip6t
+	}
+
+	return d.ipt
+}
+
+func (d *iptablesDataplane) Teardown() {
+	d.flushAndDeleteChain(constants.FilterTable, constants.ForwardChain, forwardingSubmarinerFWDChain)
+	d.flushAndDeleteChain(constants.NATTable, constants.PostRoutingChain, constants.SmPostRoutingChain)
+}
+
+func (d *iptablesDataplane) flushAndDeleteChain(table, tableChain, submarinerChain string) {
+	for _, ipt := range []iptables.Interface{d.ipt, d.ip6t} {
+		klog.Infof("Flushing iptable entries in %q chain of %q table", submarinerChain, table)
+
+		if err := ipt.ClearChain(table, submarinerChain); err != nil {
+			klog.Errorf("Error flushing iptables chain %q of %q table: %v", submarinerChain, table, err)
+		}
+
+		klog.Infof("Deleting iptable entry in %q chain of %q table", tableChain, table)
+
+		if err := ipt.Delete(table, tableChain, "-j", submarinerChain); err != nil {
+			klog.Errorf("Error deleting iptables rule from %q chain: %v", tableChain, err)
+		}
+
+		klog.Infof("Deleting iptable %q chain of %q table", submarinerChain, table)
+
+		if err := ipt.DeleteChain(table, submarinerChain); err != nil {
+			klog.Errorf("Error deleting iptable chain %q of table %q: %v", submarinerChain, table, err)
+		}
+	}
+}
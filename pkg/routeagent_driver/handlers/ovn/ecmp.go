@@ -0,0 +1,113 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovn
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+	"github.com/submariner-io/submariner/pkg/routeagent_driver/constants"
+	"github.com/vishvananda/netlink"
+)
+
+// reconcileECMPRoutes programs, in the host route agent's inter-cluster routing table, one
+// nexthop per active gateway endpoint of clusterID for each of its subnets, so traffic to that
+// cluster is spread across every currently active gateway instead of pinned to a single one. It's
+// a no-op unless ECMPEnabled is set. staleSubnets are withdrawn outright: they're no longer
+// advertised by any remaining endpoint for the cluster, so there's nothing left to spread traffic
+// across. This only programs the host-side route; the corresponding OVN Logical_Router_Static_Route
+// nexthops are reconciled independently by GatewayRouteController.
+func (ovn *Handler) reconcileECMPRoutes(clusterID string, staleSubnets []string) error {
+	if !ovn.ECMPEnabled {
+		return nil
+	}
+
+	for _, subnet := range staleSubnets {
+		if err := withdrawECMPRoute(subnet); err != nil {
+			return err
+		}
+	}
+
+	endpoints := ovn.activeEndpointsForCluster(clusterID)
+
+	subnets := map[string]bool{}
+	for _, endpoint := range endpoints {
+		for _, subnet := range endpoint.Spec.Subnets {
+			subnets[subnet] = true
+		}
+	}
+
+	nexthops := make([]*netlink.NexthopInfo, 0, len(endpoints))
+
+	for _, endpoint := range endpoints {
+		if gatewayIP := net.ParseIP(endpoint.Spec.PrivateIP); gatewayIP != nil {
+			nexthops = append(nexthops, &netlink.NexthopInfo{Gw: gatewayIP})
+		}
+	}
+
+	for subnet := range subnets {
+		if err := installECMPRoute(subnet, nexthops); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// installECMPRoute programs a single-gateway route when only one nexthop is active, and a
+// MultiPath route spreading traffic across every nexthop otherwise.
+func installECMPRoute(subnet string, nexthops []*netlink.NexthopInfo) error {
+	if len(nexthops) == 0 {
+		return withdrawECMPRoute(subnet)
+	}
+
+	_, dst, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return errors.Wrapf(err, "error parsing subnet %q for ECMP route", subnet)
+	}
+
+	route := &netlink.Route{Dst: dst, Table: constants.RouteAgentInterClusterNetworkTableID}
+
+	if len(nexthops) == 1 {
+		route.Gw = nexthops[0].Gw
+	} else {
+		route.MultiPath = nexthops
+	}
+
+	if err := netlink.RouteReplace(route); err != nil {
+		return errors.Wrapf(err, "error programming ECMP route for subnet %q", subnet)
+	}
+
+	return nil
+}
+
+func withdrawECMPRoute(subnet string) error {
+	_, dst, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return errors.Wrapf(err, "error parsing subnet %q for ECMP route", subnet)
+	}
+
+	route := &netlink.Route{Dst: dst, Table: constants.RouteAgentInterClusterNetworkTableID}
+
+	if err := netlink.RouteDel(route); err != nil {
+		return errors.Wrapf(err, "error withdrawing ECMP route for subnet %q", subnet)
+	}
+
+	return nil
+}
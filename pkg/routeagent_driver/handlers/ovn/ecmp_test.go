@@ -0,0 +1,31 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovn
+
+import "testing"
+
+func TestReconcileECMPRoutesDisabled(t *testing.T) {
+	t.Parallel()
+
+	ovn := &Handler{}
+
+	if err := ovn.reconcileECMPRoutes("east", []string{"not-a-cidr"}); err != nil {
+		t.Fatalf("expected no-op (and no error) when ECMPEnabled is false, got %v", err)
+	}
+}
@@ -0,0 +1,273 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovn
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	gatewayLeaseDuration = 15 * time.Second
+	gatewayRenewDeadline = 10 * time.Second
+	gatewayRetryPeriod   = 2 * time.Second
+)
+
+// HealthCheckConfig configures the bidirectional health probe used to detect a broken tunnel to
+// a remote cluster's gateway so a standby node can take over without waiting for the CableEngine.
+type HealthCheckConfig struct {
+	// Interval between probes of the remote gateway's public IP.
+	Interval time.Duration
+	// Timeout for a single probe.
+	Timeout time.Duration
+	// Port is the TCP port on the remote gateway's public IP that the probe connects to. There's
+	// no built-in default: it has to match whatever the deployed cable driver actually listens on
+	// (e.g. the IPsec NAT-T port or the wireguard listen port), which this handler has no way to
+	// infer on its own.
+	Port int
+}
+
+// GatewayElector arbitrates, via leader election against a Lease, which of the eligible gateway
+// nodes owns the active gateway role for a single remote cluster's subnets. On leader loss (or a
+// failed health probe of the remote gateway) this node's no-masquerade rules for that cluster are
+// withdrawn; on leader acquisition they're re-applied, giving faster failover than waiting for
+// the CableEngine to notice. Each GatewayElector is scoped to exactly one clusterID so winning or
+// losing one cluster's election never affects another cluster's dataplane state.
+type GatewayElector struct {
+	clusterID string
+	handler   *Handler
+	cancel    context.CancelFunc
+}
+
+// ensureGatewayElector starts a GatewayElector for clusterID if one isn't already running.
+func (ovn *Handler) ensureGatewayElector(clusterID string) error {
+	if _, ok := ovn.gatewayElectors[clusterID]; ok {
+		return nil
+	}
+
+	elector, err := newGatewayElector(ovn, clusterID)
+	if err != nil {
+		return err
+	}
+
+	ovn.gatewayElectors[clusterID] = elector
+
+	return nil
+}
+
+// stopGatewayElector stops and forgets the GatewayElector for clusterID, if any.
+func (ovn *Handler) stopGatewayElector(clusterID string) {
+	if elector, ok := ovn.gatewayElectors[clusterID]; ok {
+		elector.cancel()
+		delete(ovn.gatewayElectors, clusterID)
+	}
+}
+
+func newGatewayElector(handler *Handler, clusterID string) (*GatewayElector, error) {
+	identity, err := os.Hostname()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error determining node identity for gateway election")
+	}
+
+	lock, err := resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		handler.Namespace,
+		fmt.Sprintf("submariner-gateway-%s", clusterID),
+		handler.K8sClient.CoreV1(),
+		handler.K8sClient.CoordinationV1(),
+		resourcelock.ResourceLockConfig{
+			Identity:      identity,
+			EventRecorder: record.NewFakeRecorder(100),
+		},
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating resource lock for cluster %q", clusterID)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	elector := &GatewayElector{clusterID: clusterID, handler: handler, cancel: cancel}
+
+	leaderElector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: gatewayLeaseDuration,
+		RenewDeadline: gatewayRenewDeadline,
+		RetryPeriod:   gatewayRetryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				if err := handler.onGatewayLeadershipAcquired(clusterID); err != nil {
+					logger.Errorf(err, "Error applying no-masquerade rules for cluster %q", clusterID)
+				}
+			},
+			OnStoppedLeading: func() {
+				if err := handler.onGatewayLeadershipLost(clusterID); err != nil {
+					logger.Errorf(err, "Error removing no-masquerade rules for cluster %q", clusterID)
+				}
+			},
+		},
+	})
+	if err != nil {
+		cancel()
+		return nil, errors.Wrapf(err, "error creating leader elector for cluster %q", clusterID)
+	}
+
+	go elector.run(ctx, leaderElector)
+
+	return elector, nil
+}
+
+// run repeatedly runs leaderElector against ctx, which only stopGatewayElector cancels. Each
+// attempt gets its own child context so a health-probe failure can release leadership by
+// cancelling just that attempt, instead of tearing down the elector for good the way cancelling
+// ctx itself would. When HealthCheckConfig is set, the next attempt only starts once the remote
+// gateways are reachable again, so the lease this node stopped renewing can expire and be taken
+// over by a standby node meanwhile.
+func (e *GatewayElector) run(ctx context.Context, leaderElector *leaderelection.LeaderElector) {
+	for ctx.Err() == nil {
+		attemptCtx, release := context.WithCancel(ctx)
+
+		if e.handler.HealthCheckConfig != nil {
+			go e.runHealthProbe(attemptCtx, leaderElector, release)
+		}
+
+		leaderElector.Run(attemptCtx)
+		release()
+
+		for e.handler.HealthCheckConfig != nil && ctx.Err() == nil && !e.handler.remoteGatewaysReachable(e.clusterID) {
+			select {
+			case <-ctx.Done():
+			case <-time.After(e.handler.HealthCheckConfig.Interval):
+			}
+		}
+	}
+}
+
+// runHealthProbe periodically probes the active remote gateway endpoints for clusterID and
+// voluntarily cancels release, ending only the current election attempt, when the tunnel path is
+// unhealthy, so a standby node can take over within seconds and this node retries electing itself
+// once the tunnel recovers.
+func (e *GatewayElector) runHealthProbe(ctx context.Context, elected *leaderelection.LeaderElector, release context.CancelFunc) {
+	ticker := time.NewTicker(e.handler.HealthCheckConfig.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !elected.IsLeader() {
+				continue
+			}
+
+			if !e.handler.remoteGatewaysReachable(e.clusterID) {
+				logger.Warningf("Tunnel to remote cluster %q gateway is unhealthy; releasing leadership to retry election",
+					e.clusterID)
+				release()
+
+				return
+			}
+		}
+	}
+}
+
+// onGatewayLeadershipAcquired marks this node as the active gateway for clusterID only, and
+// applies the no-masquerade rules for that cluster's subnets. It must not touch ovn.isGateway or
+// call the node-wide updateGatewayDataplane/cleanupGatewayDataplane, since those cover every
+// remote cluster: winning leadership for one cluster must not affect the dataplane state of any
+// other cluster this node isn't (or is independently) the active gateway for.
+func (ovn *Handler) onGatewayLeadershipAcquired(clusterID string) error {
+	ovn.mutex.Lock()
+	defer ovn.mutex.Unlock()
+
+	ovn.activeGatewayClusters[clusterID] = true
+
+	endpoints := ovn.activeEndpointsForCluster(clusterID)
+
+	for _, endpoint := range endpoints {
+		for _, subnet := range endpoint.Spec.Subnets {
+			if err := ovn.dataplane.AddNoMasqueradeRule(subnet); err != nil {
+				return errors.Wrapf(err, "error adding no-masquerade rules for subnet %q", subnet)
+			}
+		}
+	}
+
+	if err := ovn.refreshPodSNATForSubnets(subnetsOf(endpoints), true); err != nil {
+		return errors.Wrapf(err, "error adding pod SNAT rules for cluster %q", clusterID)
+	}
+
+	return nil
+}
+
+// onGatewayLeadershipLost is the inverse of onGatewayLeadershipAcquired: it only withdraws the
+// no-masquerade rules this node installed for clusterID, leaving every other cluster's dataplane
+// state (including ones this node remains the active gateway for) untouched.
+func (ovn *Handler) onGatewayLeadershipLost(clusterID string) error {
+	ovn.mutex.Lock()
+	defer ovn.mutex.Unlock()
+
+	delete(ovn.activeGatewayClusters, clusterID)
+
+	endpoints := ovn.activeEndpointsForCluster(clusterID)
+
+	for _, endpoint := range endpoints {
+		for _, subnet := range endpoint.Spec.Subnets {
+			if err := ovn.dataplane.RemoveNoMasqueradeRule(subnet); err != nil {
+				return errors.Wrapf(err, "error removing no-masquerade rules for subnet %q", subnet)
+			}
+		}
+	}
+
+	if err := ovn.refreshPodSNATForSubnets(subnetsOf(endpoints), false); err != nil {
+		return errors.Wrapf(err, "error removing pod SNAT rules for cluster %q", clusterID)
+	}
+
+	return nil
+}
+
+// remoteGatewaysReachable probes every active remote gateway endpoint's public IP for clusterID
+// and reports whether the tunnel path is currently healthy. It takes ovn.mutex like every other
+// reader of ovn.remoteEndpoints, since it runs from the unlocked health-probe goroutine.
+func (ovn *Handler) remoteGatewaysReachable(clusterID string) bool {
+	ovn.mutex.Lock()
+	endpoints := ovn.activeEndpointsForCluster(clusterID)
+	ovn.mutex.Unlock()
+
+	timeout := ovn.HealthCheckConfig.Timeout
+	port := strconv.Itoa(ovn.HealthCheckConfig.Port)
+
+	for _, endpoint := range endpoints {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(endpoint.Spec.PublicIP, port), timeout)
+		if err != nil {
+			return false
+		}
+
+		_ = conn.Close()
+	}
+
+	return true
+}
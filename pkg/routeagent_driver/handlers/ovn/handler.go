@@ -51,6 +51,31 @@ type HandlerConfig struct {
 	DynClient      dynamic.Interface
 	WatcherConfig  *watcher.Config
 	NewOVSDBClient NewOVSDBClientFn
+	// ECMPEnabled, when set, causes the handler to program one nexthop per active gateway
+	// endpoint of a remote cluster instead of picking a single one, spreading traffic to
+	// that cluster's subnets across every currently active gateway.
+	ECMPEnabled bool
+	// PodSNATAnnotationKey and PodSNATAnnotationValue identify pods that should be SNATed to
+	// the gateway node IP for egress to remote cluster subnets, rather than having their pod
+	// IP preserved. Left unset, PodSNATAnnotationKey defaults to podSNATAnnotationKey and
+	// behaviour is opt-in per pod so existing clusters are unaffected.
+	PodSNATAnnotationKey   string
+	PodSNATAnnotationValue string
+	// GatewayHAEnabled arbitrates the active gateway dataplane role per remote cluster via
+	// leader election instead of relying solely on TransitionToGateway/TransitionToNonGateway,
+	// and voluntarily releases leadership when HealthCheckConfig detects the tunnel is down.
+	GatewayHAEnabled  bool
+	HealthCheckConfig *HealthCheckConfig
+	// CableRoutingInterfaces is an ordered list of candidate secondary-network interfaces to
+	// route cable traffic over, resolved by name or CIDR match. Selectors that give only a
+	// NetworkAttachmentDefinition reference are not resolved; they're logged and skipped. When
+	// empty, the handler keeps its historical behaviour of routing over the wireguard device or
+	// the default gateway interface only.
+	CableRoutingInterfaces []CableRoutingInterfaceSelector
+	// DataplaneBackend selects the implementation used for the Submariner forward/postrouting
+	// chains: "iptables", "nftables", or "auto" to detect which the host is actually running.
+	// Defaults to "auto" when unset.
+	DataplaneBackend string
 }
 
 type Handler struct {
@@ -62,8 +87,15 @@ type Handler struct {
 	isGateway                 bool
 	netLink                   netlink.Interface
 	ipt                       iptables.Interface
+	ip6t                      iptables.Interface
 	gatewayRouteController    *GatewayRouteController
 	nonGatewayRouteController *NonGatewayRouteController
+	podWatcher                *PodWatcher
+	snatPods                  map[string]string
+	gatewayElectors           map[string]*GatewayElector
+	activeGatewayClusters     map[string]bool
+	secondaryInterfaces       map[string]*cableRoutingInterface
+	dataplane                 Dataplane
 	stopCh                    chan struct{}
 }
 
@@ -76,18 +108,43 @@ func NewHandler(config *HandlerConfig) *Handler {
 		logger.Fatalf("Error initializing iptables in OVN routeagent handler: %s", err)
 	}
 
+	ip6t, err := iptables.NewIPv6()
+	if err != nil {
+		logger.Fatalf("Error initializing ip6tables in OVN routeagent handler: %s", err)
+	}
+
 	h := &Handler{
-		HandlerConfig:   *config,
-		remoteEndpoints: map[string]*submV1.Endpoint{},
-		netLink:         netlink.New(),
-		ipt:             ipt,
-		stopCh:          make(chan struct{}),
+		HandlerConfig:         *config,
+		remoteEndpoints:       map[string]*submV1.Endpoint{},
+		netLink:               netlink.New(),
+		ipt:                   ipt,
+		ip6t:                  ip6t,
+		snatPods:              map[string]string{},
+		gatewayElectors:       map[string]*GatewayElector{},
+		activeGatewayClusters: map[string]bool{},
+		secondaryInterfaces:   map[string]*cableRoutingInterface{},
+		stopCh:                make(chan struct{}),
 	}
 
 	if h.NewOVSDBClient == nil {
 		h.NewOVSDBClient = libovsdbclient.NewOVSDBClient
 	}
 
+	if h.PodSNATAnnotationKey == "" {
+		h.PodSNATAnnotationKey = podSNATAnnotationKey
+	}
+
+	if h.PodSNATAnnotationValue == "" {
+		h.PodSNATAnnotationValue = podSNATAnnotationValue
+	}
+
+	dataplane, err := NewDataplane(h.DataplaneBackend, ipt, ip6t)
+	if err != nil {
+		logger.Fatalf("Error initializing dataplane backend for OVN routeagent handler: %s", err)
+	}
+
+	h.dataplane = dataplane
+
 	return h
 }
 
@@ -99,19 +156,39 @@ func (ovn *Handler) GetNetworkPlugins() []string {
 	return []string{cni.OVNKubernetes}
 }
 
+// activeEndpointsForCluster returns every remote Endpoint currently known for the given
+// cluster. When ECMPEnabled is set and more than one is returned, reconcileECMPRoutes programs
+// one nexthop per endpoint instead of a single route.
+func (ovn *Handler) activeEndpointsForCluster(clusterID string) []*submV1.Endpoint {
+	endpoints := make([]*submV1.Endpoint, 0, len(ovn.remoteEndpoints))
+
+	for _, endpoint := range ovn.remoteEndpoints {
+		if endpoint.Spec.ClusterID == clusterID {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	return endpoints
+}
+
 func (ovn *Handler) Init() error {
 	ovn.LegacyCleanup()
 
-	err := ovn.initIPtablesChains()
-	if err != nil {
-		return err
+	if ovn.dataplane.Backend() == DataplaneBackendIPTables {
+		if err := ovn.initIPtablesChains(); err != nil {
+			return err
+		}
+	}
+
+	if err := ovn.dataplane.EnsureSubmarinerChains(); err != nil {
+		return errors.Wrapf(err, "error ensuring dataplane chains")
 	}
 
 	ovn.startRouteConfigSyncer(ovn.stopCh)
 
 	connectionHandler := NewConnectionHandler(ovn.K8sClient, ovn.DynClient)
 
-	err = connectionHandler.initClients(ovn.NewOVSDBClient)
+	err := connectionHandler.initClients(ovn.NewOVSDBClient)
 	if err != nil {
 		return errors.Wrapf(err, "error getting connection handler to connect to OvnDB")
 	}
@@ -134,6 +211,13 @@ func (ovn *Handler) Init() error {
 
 	ovn.nonGatewayRouteController = nonGatewayRouteController
 
+	podWatcher, err := NewPodWatcher(*ovn.WatcherConfig, ovn, ovn.stopCh)
+	if err != nil {
+		return errors.Wrapf(err, "error starting pod watcher for per-pod SNAT")
+	}
+
+	ovn.podWatcher = podWatcher
+
 	return err
 }
 
@@ -158,6 +242,16 @@ func (ovn *Handler) LocalEndpointCreated(endpoint *submV1.Endpoint) error {
 
 	ovn.cableRoutingInterface = routingInterface
 
+	if len(ovn.CableRoutingInterfaces) > 0 {
+		if err := ovn.resolveSecondaryCableRoutingInterfaces(); err != nil {
+			return errors.Wrapf(err, "error resolving secondary cable routing interfaces")
+		}
+
+		if err := ovn.reconcileSecondaryInterfaceRoutes(ovn.allRemoteSubnets(), nil); err != nil {
+			return errors.Wrapf(err, "error installing secondary interface routes for already-known remote subnets")
+		}
+	}
+
 	return nil
 }
 
@@ -174,18 +268,36 @@ func (ovn *Handler) RemoteEndpointCreated(endpoint *submV1.Endpoint) error {
 
 	ovn.remoteEndpoints[endpoint.Name] = endpoint
 
+	if ovn.GatewayHAEnabled {
+		if err := ovn.ensureGatewayElector(endpoint.Spec.ClusterID); err != nil {
+			return errors.Wrapf(err, "error starting gateway elector for cluster %q", endpoint.Spec.ClusterID)
+		}
+	}
+
 	err := ovn.updateHostNetworkDataplane()
 	if err != nil {
 		return errors.Wrapf(err, "updateHostNetworkDataplane returned error")
 	}
 
+	if err = ovn.reconcileSecondaryInterfaceRoutes(endpoint.Spec.Subnets, nil); err != nil {
+		return errors.Wrapf(err, "error reconciling secondary interface routes for cluster %q", endpoint.Spec.ClusterID)
+	}
+
 	if ovn.isGateway {
 		for _, subnet := range endpoint.Spec.Subnets {
-			if err = ovn.addNoMasqueradeIPTables(subnet); err != nil {
+			if err = ovn.dataplane.AddNoMasqueradeRule(subnet); err != nil {
 				return errors.Wrapf(err, "error adding no-masquerade rules for subnet %q", subnet)
 			}
 		}
 
+		if err = ovn.refreshPodSNATForSubnets(endpoint.Spec.Subnets, true); err != nil {
+			return errors.Wrapf(err, "error adding pod SNAT rules for new remote subnets")
+		}
+
+		if err = ovn.reconcileECMPRoutes(endpoint.Spec.ClusterID, nil); err != nil {
+			return errors.Wrapf(err, "error reconciling ECMP routes for cluster %q", endpoint.Spec.ClusterID)
+		}
+
 		return ovn.updateGatewayDataplane()
 	}
 
@@ -209,6 +321,10 @@ func (ovn *Handler) RemoteEndpointUpdated(endpoint *submV1.Endpoint) error {
 		return errors.Wrapf(err, "updateHostNetworkDataplane returned error")
 	}
 
+	if err = ovn.reconcileSecondaryInterfaceRoutes(endpoint.Spec.Subnets, nil); err != nil {
+		return errors.Wrapf(err, "error reconciling secondary interface routes for cluster %q", endpoint.Spec.ClusterID)
+	}
+
 	if ovn.isGateway {
 		return ovn.updateGatewayDataplane()
 	}
@@ -216,58 +332,127 @@ func (ovn *Handler) RemoteEndpointUpdated(endpoint *submV1.Endpoint) error {
 	return nil
 }
 
+// RemoteEndpointRemoved drops the given Endpoint from the remote endpoint set and recomputes
+// the dataplane. When another active endpoint for the same cluster still advertises one of the
+// removed endpoint's subnets (with ECMPEnabled, as a separate nexthop), that subnet's
+// no-masquerade and pod-SNAT rules are left in place; only subnets no longer advertised by any
+// remaining endpoint for the cluster are torn down. This holds whether or not ECMPEnabled is set.
 func (ovn *Handler) RemoteEndpointRemoved(endpoint *submV1.Endpoint) error {
 	ovn.mutex.Lock()
 	defer ovn.mutex.Unlock()
 
 	delete(ovn.remoteEndpoints, endpoint.Name)
 
+	remainingForCluster := ovn.activeEndpointsForCluster(endpoint.Spec.ClusterID)
+
+	if ovn.GatewayHAEnabled && len(remainingForCluster) == 0 {
+		ovn.stopGatewayElector(endpoint.Spec.ClusterID)
+	}
+
 	err := ovn.updateHostNetworkDataplane()
 	if err != nil {
 		return errors.Wrapf(err, "updateHostNetworkDataplane returned error")
 	}
 
+	staleSubnets := subnetsNoLongerCovered(endpoint.Spec.Subnets, remainingForCluster)
+
+	if err = ovn.reconcileSecondaryInterfaceRoutes(nil, staleSubnets); err != nil {
+		return errors.Wrapf(err, "error reconciling secondary interface routes for cluster %q", endpoint.Spec.ClusterID)
+	}
+
 	if ovn.isGateway {
-		for _, subnet := range endpoint.Spec.Subnets {
-			if err = ovn.removeNoMasqueradeIPTables(subnet); err != nil {
+		for _, subnet := range staleSubnets {
+			if err = ovn.dataplane.RemoveNoMasqueradeRule(subnet); err != nil {
 				return errors.Wrapf(err, "error removing no-masquerade rules for subnet %q", subnet)
 			}
 		}
 
+		if err = ovn.refreshPodSNATForSubnets(staleSubnets, false); err != nil {
+			return errors.Wrapf(err, "error removing pod SNAT rules for departed remote subnets")
+		}
+
+		if err = ovn.reconcileECMPRoutes(endpoint.Spec.ClusterID, staleSubnets); err != nil {
+			return errors.Wrapf(err, "error reconciling ECMP routes for cluster %q", endpoint.Spec.ClusterID)
+		}
+
 		return ovn.updateGatewayDataplane()
 	}
 
 	return nil
 }
 
+// subnetsNoLongerCovered returns the subset of removedSubnets that none of the remaining
+// endpoints for the same cluster advertise. When multiple endpoints for a cluster advertise the
+// same subnet (with ECMPEnabled, as separate nexthops), a subnet stays covered (and is excluded
+// from the result) as long as at least one of those endpoints remains; ECMPEnabled isn't consulted.
+func subnetsNoLongerCovered(removedSubnets []string, remaining []*submV1.Endpoint) []string {
+	covered := make(map[string]bool)
+
+	for _, endpoint := range remaining {
+		for _, subnet := range endpoint.Spec.Subnets {
+			covered[subnet] = true
+		}
+	}
+
+	stale := make([]string, 0, len(removedSubnets))
+
+	for _, subnet := range removedSubnets {
+		if !covered[subnet] {
+			stale = append(stale, subnet)
+		}
+	}
+
+	return stale
+}
+
+// TransitionToNonGateway handles the CableEngine removing this node's gateway role. When
+// GatewayHAEnabled is set, each remote cluster's no-masquerade/pod-SNAT rules are instead
+// arbitrated by that cluster's GatewayElector via onGatewayLeadershipAcquired/Lost, so applying
+// them here unconditionally would fight over the same rules with whichever node the election
+// currently favours; only the node-wide OVN dataplane teardown still runs in that case.
 func (ovn *Handler) TransitionToNonGateway() error {
 	ovn.mutex.Lock()
 	defer ovn.mutex.Unlock()
 
 	ovn.isGateway = false
-	for _, endpoint := range ovn.remoteEndpoints {
-		for _, subnet := range endpoint.Spec.Subnets {
-			if err := ovn.removeNoMasqueradeIPTables(subnet); err != nil {
-				return errors.Wrapf(err, "error removing no-masquerade rules for subnet %q", subnet)
+
+	if !ovn.GatewayHAEnabled {
+		for _, endpoint := range ovn.remoteEndpoints {
+			for _, subnet := range endpoint.Spec.Subnets {
+				if err := ovn.dataplane.RemoveNoMasqueradeRule(subnet); err != nil {
+					return errors.Wrapf(err, "error removing no-masquerade rules for subnet %q", subnet)
+				}
 			}
 		}
+
+		if err := ovn.refreshPodSNATForSubnets(ovn.allRemoteSubnets(), false); err != nil {
+			return errors.Wrapf(err, "error removing pod SNAT rules on gateway transition")
+		}
 	}
 
 	return ovn.cleanupGatewayDataplane()
 }
 
+// TransitionToGateway is the inverse of TransitionToNonGateway; see its doc comment for why the
+// per-cluster rule changes are skipped when GatewayHAEnabled arbitrates them instead.
 func (ovn *Handler) TransitionToGateway() error {
 	ovn.mutex.Lock()
 	defer ovn.mutex.Unlock()
 
 	ovn.isGateway = true
 
-	for _, endpoint := range ovn.remoteEndpoints {
-		for _, subnet := range endpoint.Spec.Subnets {
-			if err := ovn.addNoMasqueradeIPTables(subnet); err != nil {
-				return errors.Wrapf(err, "error adding no-masquerade rules for subnet %q", subnet)
+	if !ovn.GatewayHAEnabled {
+		for _, endpoint := range ovn.remoteEndpoints {
+			for _, subnet := range endpoint.Spec.Subnets {
+				if err := ovn.dataplane.AddNoMasqueradeRule(subnet); err != nil {
+					return errors.Wrapf(err, "error adding no-masquerade rules for subnet %q", subnet)
+				}
 			}
 		}
+
+		if err := ovn.refreshPodSNATForSubnets(ovn.allRemoteSubnets(), true); err != nil {
+			return errors.Wrapf(err, "error adding pod SNAT rules on gateway transition")
+		}
 	}
 
 	return ovn.updateGatewayDataplane()
@@ -0,0 +1,74 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovn
+
+import (
+	"reflect"
+	"testing"
+
+	submV1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+)
+
+func TestSubnetsNoLongerCovered(t *testing.T) {
+	t.Parallel()
+
+	endpointWithSubnets := func(subnets ...string) *submV1.Endpoint {
+		return &submV1.Endpoint{Spec: submV1.EndpointSpec{Subnets: subnets}}
+	}
+
+	tests := []struct {
+		name      string
+		removed   []string
+		remaining []*submV1.Endpoint
+		want      []string
+	}{
+		{
+			name:      "no remaining endpoints",
+			removed:   []string{"10.0.0.0/24", "10.0.1.0/24"},
+			remaining: nil,
+			want:      []string{"10.0.0.0/24", "10.0.1.0/24"},
+		},
+		{
+			name:      "remaining endpoint covers all removed subnets",
+			removed:   []string{"10.0.0.0/24"},
+			remaining: []*submV1.Endpoint{endpointWithSubnets("10.0.0.0/24")},
+			want:      []string{},
+		},
+		{
+			name:      "remaining endpoint covers only some removed subnets",
+			removed:   []string{"10.0.0.0/24", "10.0.1.0/24"},
+			remaining: []*submV1.Endpoint{endpointWithSubnets("10.0.0.0/24")},
+			want:      []string{"10.0.1.0/24"},
+		},
+	}
+
+	for i := range tests {
+		tt := tests[i]
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := subnetsNoLongerCovered(tt.removed, tt.remaining)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
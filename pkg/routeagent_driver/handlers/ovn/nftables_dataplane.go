@@ -0,0 +1,261 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovn
+
+import (
+	"net"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/expr"
+	"github.com/pkg/errors"
+)
+
+const (
+	nftablesSubmarinerTable    = "submariner"
+	nftablesForwardChain       = "forward"
+	nftablesPostroutingChain   = "postrouting"
+	nftablesSubmarinerPriority = 0
+)
+
+// nftablesDataplane is a Dataplane implementation built on github.com/google/nftables. It owns
+// a dedicated "submariner" table with "forward" and "postrouting" chains hooked at the same
+// points the iptables backend's forwardingSubmarinerFWDChain/SmPostRoutingChain are jumped to,
+// so the two backends are behaviourally equivalent.
+type nftablesDataplane struct {
+	conn     *nftables.Conn
+	table    *nftables.Table
+	forward  *nftables.Chain
+	postrtg  *nftables.Chain
+	noMasqBy map[string]*nftables.Rule
+	snatBy   map[string]*nftables.Rule
+}
+
+func newNFTablesDataplane() (*nftablesDataplane, error) {
+	conn, err := nftables.New()
+	if err != nil {
+		return nil, errors.Wrap(err, "error connecting to nftables")
+	}
+
+	return &nftablesDataplane{conn: conn, noMasqBy: map[string]*nftables.Rule{}, snatBy: map[string]*nftables.Rule{}}, nil
+}
+
+func (d *nftablesDataplane) Backend() string {
+	return DataplaneBackendNFTables
+}
+
+func (d *nftablesDataplane) EnsureSubmarinerChains() error {
+	d.table = d.conn.AddTable(&nftables.Table{
+		Name:   nftablesSubmarinerTable,
+		Family: nftables.TableFamilyINet,
+	})
+
+	forwardPolicy := nftables.ChainPolicyAccept
+
+	d.forward = d.conn.AddChain(&nftables.Chain{
+		Name:     nftablesForwardChain,
+		Table:    d.table,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookForward,
+		Priority: nftables.ChainPriorityFilter,
+		Policy:   &forwardPolicy,
+	})
+
+	postroutingPolicy := nftables.ChainPolicyAccept
+
+	d.postrtg = d.conn.AddChain(&nftables.Chain{
+		Name:     nftablesPostroutingChain,
+		Table:    d.table,
+		Type:     nftables.ChainTypeNAT,
+		Hooknum:  nftables.ChainHookPostrouting,
+		Priority: nftables.ChainPriorityNATSource,
+		Policy:   &postroutingPolicy,
+	})
+
+	if err := d.conn.Flush(); err != nil {
+		return errors.Wrap(err, "error creating nftables submariner table/chains")
+	}
+
+	return nil
+}
+
+// AddNoMasqueradeRule mirrors the iptables backend's "-d <subnet> -j ACCEPT" rule: match
+// destination address against subnet (masked) and accept, which nft evaluates ahead of any
+// later masquerade rule in the same postrouting chain.
+func (d *nftablesDataplane) AddNoMasqueradeRule(subnet string) error {
+	exprs, err := destinationCIDRExprs(subnet)
+	if err != nil {
+		return errors.Wrapf(err, "error building no-masquerade rule for subnet %q", subnet)
+	}
+
+	rule := d.conn.AddRule(&nftables.Rule{
+		Table: d.table,
+		Chain: d.postrtg,
+		Exprs: append(exprs, &expr.Verdict{Kind: expr.VerdictAccept}),
+	})
+
+	if err := d.conn.Flush(); err != nil {
+		return errors.Wrapf(err, "error adding no-masquerade rule for subnet %q", subnet)
+	}
+
+	d.noMasqBy[subnet] = rule
+
+	return nil
+}
+
+func (d *nftablesDataplane) RemoveNoMasqueradeRule(subnet string) error {
+	rule, ok := d.noMasqBy[subnet]
+	if !ok {
+		return nil
+	}
+
+	if err := d.conn.DelRule(rule); err != nil {
+		return errors.Wrapf(err, "error queuing deletion of no-masquerade rule for subnet %q", subnet)
+	}
+
+	if err := d.conn.Flush(); err != nil {
+		return errors.Wrapf(err, "error removing no-masquerade rule for subnet %q", subnet)
+	}
+
+	delete(d.noMasqBy, subnet)
+
+	return nil
+}
+
+// AddPodSNATRule masquerades traffic from podIP to subnet, mirroring the iptables backend's
+// "-s <podIP> -d <subnet> -j MASQUERADE" rule.
+func (d *nftablesDataplane) AddPodSNATRule(podIP, subnet string) error {
+	exprs, err := podSNATExprs(podIP, subnet)
+	if err != nil {
+		return errors.Wrapf(err, "error building pod SNAT rule for pod %q/subnet %q", podIP, subnet)
+	}
+
+	rule := d.conn.AddRule(&nftables.Rule{
+		Table: d.table,
+		Chain: d.postrtg,
+		Exprs: append(exprs, &expr.Masq{}),
+	})
+
+	if err := d.conn.Flush(); err != nil {
+		return errors.Wrapf(err, "error adding pod SNAT rule for pod %q/subnet %q", podIP, subnet)
+	}
+
+	d.snatBy[podSNATKey(podIP, subnet)] = rule
+
+	return nil
+}
+
+func (d *nftablesDataplane) RemovePodSNATRule(podIP, subnet string) error {
+	key := podSNATKey(podIP, subnet)
+
+	rule, ok := d.snatBy[key]
+	if !ok {
+		return nil
+	}
+
+	if err := d.conn.DelRule(rule); err != nil {
+		return errors.Wrapf(err, "error queuing deletion of pod SNAT rule for pod %q/subnet %q", podIP, subnet)
+	}
+
+	if err := d.conn.Flush(); err != nil {
+		return errors.Wrapf(err, "error removing pod SNAT rule for pod %q/subnet %q", podIP, subnet)
+	}
+
+	delete(d.snatBy, key)
+
+	return nil
+}
+
+func podSNATKey(podIP, subnet string) string {
+	return podIP + "/" + subnet
+}
+
+func (d *nftablesDataplane) Teardown() {
+	if d.table == nil {
+		return
+	}
+
+	// Deleting the table removes every chain and rule created under it in one shot.
+	d.conn.DelTable(d.table)
+
+	if err := d.conn.Flush(); err != nil {
+		logger.Errorf(err, "Error deleting nftables submariner table")
+	}
+}
+
+// destinationCIDRExprs builds the nft match expression for "destination address is within
+// subnet", selecting the IPv4 or IPv6 payload offset/length based on the CIDR's family.
+func destinationCIDRExprs(subnet string) ([]expr.Any, error) {
+	_, ipNet, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing CIDR %q", subnet)
+	}
+
+	if ip4 := ipNet.IP.To4(); ip4 != nil {
+		return []expr.Any{
+			&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: 16, Len: 4},
+			&expr.Bitwise{SourceRegister: 1, DestRegister: 1, Len: 4, Mask: ipNet.Mask, Xor: make([]byte, 4)},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: ip4.Mask(ipNet.Mask)},
+		}, nil
+	}
+
+	ip6 := ipNet.IP.To16()
+
+	return []expr.Any{
+		&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: 24, Len: 16},
+		&expr.Bitwise{SourceRegister: 1, DestRegister: 1, Len: 16, Mask: ipNet.Mask, Xor: make([]byte, 16)},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: ip6.Mask(ipNet.Mask)},
+	}, nil
+}
+
+// sourceIPExprs builds the nft match expression for "source address equals podIP exactly",
+// selecting the IPv4 or IPv6 payload offset/length based on podIP's family.
+func sourceIPExprs(podIP string) ([]expr.Any, error) {
+	ip := net.ParseIP(podIP)
+	if ip == nil {
+		return nil, errors.Errorf("error parsing pod IP %q", podIP)
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return []expr.Any{
+			&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: 12, Len: 4},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: ip4},
+		}, nil
+	}
+
+	return []expr.Any{
+		&expr.Payload{DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: 8, Len: 16},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: ip.To16()},
+	}, nil
+}
+
+// podSNATExprs builds the nft match expression for "source address is podIP and destination
+// address is within subnet", combining sourceIPExprs and destinationCIDRExprs.
+func podSNATExprs(podIP, subnet string) ([]expr.Any, error) {
+	srcExprs, err := sourceIPExprs(podIP)
+	if err != nil {
+		return nil, err
+	}
+
+	dstExprs, err := destinationCIDRExprs(subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(srcExprs, dstExprs...), nil
+}
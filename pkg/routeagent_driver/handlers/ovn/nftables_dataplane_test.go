@@ -0,0 +1,85 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovn
+
+import (
+	"testing"
+
+	"github.com/google/nftables/expr"
+)
+
+func TestDestinationCIDRExprs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		subnet     string
+		wantLen    int
+		wantBits   int
+		invalidErr bool
+	}{
+		{name: "ipv4", subnet: "10.0.0.0/24", wantLen: 4, wantBits: 4},
+		{name: "ipv6", subnet: "fd00::/64", wantLen: 16, wantBits: 16},
+		{name: "invalid CIDR", subnet: "not-a-cidr", invalidErr: true},
+	}
+
+	for i := range tests {
+		tt := tests[i]
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			exprs, err := destinationCIDRExprs(tt.subnet)
+
+			if tt.invalidErr {
+				if err == nil {
+					t.Fatalf("expected an error for subnet %q, got none", tt.subnet)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error for subnet %q: %v", tt.subnet, err)
+			}
+
+			if len(exprs) != 3 {
+				t.Fatalf("expected 3 expressions, got %d", len(exprs))
+			}
+
+			bitwise, ok := exprs[1].(*expr.Bitwise)
+			if !ok {
+				t.Fatalf("expected second expression to be *expr.Bitwise, got %T", exprs[1])
+			}
+
+			if int(bitwise.Len) != tt.wantBits {
+				t.Fatalf("expected Bitwise.Len %d, got %d", tt.wantBits, bitwise.Len)
+			}
+
+			cmp, ok := exprs[2].(*expr.Cmp)
+			if !ok {
+				t.Fatalf("expected third expression to be *expr.Cmp, got %T", exprs[2])
+			}
+
+			if len(cmp.Data) != tt.wantLen {
+				t.Fatalf("expected Cmp.Data length %d, got %d", tt.wantLen, len(cmp.Data))
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovn
+
+import (
+	"github.com/pkg/errors"
+	"github.com/submariner-io/admiral/pkg/watcher"
+	submV1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// podSNATAnnotationKey is the default annotation pods set to opt in to being SNATed to the
+	// gateway node IP for egress to remote cluster subnets, instead of preserving the pod IP.
+	podSNATAnnotationKey   = "submariner.io/snat-to-remote"
+	podSNATAnnotationValue = "true"
+)
+
+// PodWatcher watches pods cluster-wide and maintains, through the Handler's Dataplane, the
+// MASQUERADE (SNAT) rules for pods that opted in to per-pod SNAT via the configured annotation.
+type PodWatcher struct {
+	handler *Handler
+}
+
+// NewPodWatcher creates and starts a PodWatcher that reconciles per-pod SNAT rules on the
+// Handler as pods carrying the opt-in annotation are added, updated or removed.
+func NewPodWatcher(config watcher.Config, handler *Handler, stopCh <-chan struct{}) (*PodWatcher, error) {
+	podWatcher := &PodWatcher{handler: handler}
+
+	config.ResourceConfigs = []watcher.ResourceConfig{
+		{
+			Name:         "Pod SNAT watcher",
+			ResourceType: &corev1.Pod{},
+			Handler: watcher.EventHandlerFuncs{
+				OnCreateFunc: podWatcher.onPodCreatedOrUpdated,
+				OnUpdateFunc: podWatcher.onPodCreatedOrUpdated,
+				OnDeleteFunc: podWatcher.onPodRemoved,
+			},
+		},
+	}
+
+	podsWatcher, err := watcher.New(&config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error creating pod watcher")
+	}
+
+	if err := podsWatcher.Start(stopCh); err != nil {
+		return nil, errors.Wrapf(err, "error starting pod watcher")
+	}
+
+	return podWatcher, nil
+}
+
+func (w *PodWatcher) onPodCreatedOrUpdated(obj interface{}) bool {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.Status.PodIP == "" {
+		return false
+	}
+
+	wantsSNAT := pod.Annotations[w.handler.PodSNATAnnotationKey] == w.handler.PodSNATAnnotationValue
+
+	if err := w.handler.reconcilePodSNAT(pod.Name, pod.Status.PodIP, wantsSNAT); err != nil {
+		logger.Errorf(err, "Error reconciling SNAT rules for pod %q", pod.Name)
+		return true
+	}
+
+	return false
+}
+
+func (w *PodWatcher) onPodRemoved(obj interface{}) bool {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return false
+	}
+
+	if err := w.handler.reconcilePodSNAT(pod.Name, pod.Status.PodIP, false); err != nil {
+		logger.Errorf(err, "Error removing SNAT rules for pod %q", pod.Name)
+		return true
+	}
+
+	return false
+}
+
+// reconcilePodSNAT tracks whether a pod currently wants SNAT and, only while this node is the
+// active gateway, keeps its conditional MASQUERADE rules for every known remote cluster subnet in
+// sync. Pods are watched cluster-wide, so every node keeps the same desired-state bookkeeping in
+// ovn.snatPods regardless of its own gateway role; that way refreshPodSNATForSubnets can restore
+// the rules for every already opted-in pod as soon as this node becomes the active gateway,
+// instead of only catching pods whose annotation changes after failover.
+func (ovn *Handler) reconcilePodSNAT(podName, podIP string, wantsSNAT bool) error {
+	ovn.mutex.Lock()
+	defer ovn.mutex.Unlock()
+
+	previousIP, hadRule := ovn.snatPods[podName]
+
+	if hadRule && (previousIP != podIP || !wantsSNAT) {
+		if ovn.isGateway {
+			if err := ovn.removePodSNATRules(previousIP); err != nil {
+				return err
+			}
+		}
+
+		delete(ovn.snatPods, podName)
+	}
+
+	if !wantsSNAT || podIP == "" || ovn.snatPods[podName] == podIP {
+		return nil
+	}
+
+	if ovn.isGateway {
+		if err := ovn.addPodSNATRules(podIP); err != nil {
+			return err
+		}
+	}
+
+	ovn.snatPods[podName] = podIP
+
+	return nil
+}
+
+func (ovn *Handler) addPodSNATRules(podIP string) error {
+	for _, endpoint := range ovn.remoteEndpoints {
+		for _, subnet := range endpoint.Spec.Subnets {
+			if err := ovn.dataplane.AddPodSNATRule(podIP, subnet); err != nil {
+				return errors.Wrapf(err, "error adding pod SNAT rule for %q to %q", podIP, subnet)
+			}
+		}
+	}
+
+	return nil
+}
+
+// refreshPodSNATForSubnets adds or removes the per-pod SNAT rule for every already opted-in pod
+// against the given subnets. It's called as remote endpoints come and go so SNAT coverage tracks
+// the current set of remote cluster subnets without waiting for the next pod event.
+func (ovn *Handler) refreshPodSNATForSubnets(subnets []string, add bool) error {
+	for _, podIP := range ovn.snatPods {
+		for _, subnet := range subnets {
+			var err error
+			if add {
+				err = ovn.dataplane.AddPodSNATRule(podIP, subnet)
+			} else {
+				err = ovn.dataplane.RemovePodSNATRule(podIP, subnet)
+			}
+
+			if err != nil {
+				return errors.Wrapf(err, "error reconciling pod SNAT rule for %q to %q", podIP, subnet)
+			}
+		}
+	}
+
+	return nil
+}
+
+// allRemoteSubnets returns every subnet of every currently known remote Endpoint, for callers
+// that need to reconcile pod SNAT coverage against the full set rather than one endpoint's
+// subnets, e.g. on a gateway role transition.
+func (ovn *Handler) allRemoteSubnets() []string {
+	return subnetsOf(ovn.remoteEndpointSlice())
+}
+
+// remoteEndpointSlice returns every currently known remote Endpoint as a slice.
+func (ovn *Handler) remoteEndpointSlice() []*submV1.Endpoint {
+	endpoints := make([]*submV1.Endpoint, 0, len(ovn.remoteEndpoints))
+
+	for _, endpoint := range ovn.remoteEndpoints {
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints
+}
+
+// subnetsOf returns the concatenation of every Endpoint's subnets.
+func subnetsOf(endpoints []*submV1.Endpoint) []string {
+	subnets := make([]string, 0, len(endpoints))
+
+	for _, endpoint := range endpoints {
+		subnets = append(subnets, endpoint.Spec.Subnets...)
+	}
+
+	return subnets
+}
+
+func (ovn *Handler) removePodSNATRules(podIP string) error {
+	for _, endpoint := range ovn.remoteEndpoints {
+		for _, subnet := range endpoint.Spec.Subnets {
+			if err := ovn.dataplane.RemovePodSNATRule(podIP, subnet); err != nil {
+				return errors.Wrapf(err, "error removing pod SNAT rule for %q to %q", podIP, subnet)
+			}
+		}
+	}
+
+	return nil
+}
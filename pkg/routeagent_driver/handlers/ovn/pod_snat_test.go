@@ -0,0 +1,49 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovn
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	submV1 "github.com/submariner-io/submariner/pkg/apis/submariner.io/v1"
+)
+
+func TestSubnetsOf(t *testing.T) {
+	t.Parallel()
+
+	endpoints := []*submV1.Endpoint{
+		{Spec: submV1.EndpointSpec{Subnets: []string{"10.0.0.0/24"}}},
+		{Spec: submV1.EndpointSpec{Subnets: []string{"10.0.1.0/24", "fd00::/64"}}},
+	}
+
+	got := subnetsOf(endpoints)
+	sort.Strings(got)
+
+	want := []string{"10.0.0.0/24", "10.0.1.0/24", "fd00::/64"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if got := subnetsOf(nil); len(got) != 0 {
+		t.Fatalf("expected no subnets for no endpoints, got %v", got)
+	}
+}
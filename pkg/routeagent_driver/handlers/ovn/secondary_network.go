@@ -0,0 +1,132 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovn
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// secondaryRouteTableIDStart/End bound the range of routing table IDs dynamically allocated to
+// secondary cable-routing interfaces, one per interface, instead of the two fixed table IDs used
+// when only a single interface is in play.
+const (
+	secondaryRouteTableIDStart = 150
+	secondaryRouteTableIDEnd   = 250
+)
+
+// CableRoutingInterfaceSelector identifies a candidate secondary-network interface to route
+// cable traffic over. Exactly one of Name, NetworkAttachmentDefinition or CIDR should be set;
+// candidates are tried in the order they appear in HandlerConfig.CableRoutingInterfaces.
+type CableRoutingInterfaceSelector struct {
+	// Name matches the interface by its device name (e.g. "net1").
+	Name string
+	// NetworkAttachmentDefinition names a Multus NetworkAttachmentDefinition (in "namespace/name"
+	// form). It isn't resolved to a host interface: a selector that sets only this field is
+	// logged and skipped.
+	NetworkAttachmentDefinition string
+	// CIDR matches the interface carrying an address within the given CIDR.
+	CIDR string
+}
+
+// cableRoutingInterface pairs a resolved secondary-network interface with the routing table
+// allocated to it.
+type cableRoutingInterface struct {
+	iface   *net.Interface
+	tableID int
+}
+
+// resolveSecondaryCableRoutingInterfaces resolves every configured CableRoutingInterfaceSelector
+// to a host interface and allocates it a dedicated routing table ID, which
+// reconcileSecondaryInterfaceRoutes then routes remote subnets through. A selector that can't
+// be resolved (e.g. a NetworkAttachmentDefinition reference, which isn't supported) is logged
+// and skipped rather than aborting resolution of the selectors that follow it. Interfaces
+// resolved by an earlier call keep their table IDs.
+func (ovn *Handler) resolveSecondaryCableRoutingInterfaces() error {
+	nextTableID := secondaryRouteTableIDStart + len(ovn.secondaryInterfaces)
+
+	for _, selector := range ovn.CableRoutingInterfaces {
+		iface, err := resolveInterface(selector)
+		if err != nil {
+			logger.Warningf("Skipping cable routing interface selector %+v: %v", selector, err)
+			continue
+		}
+
+		if _, ok := ovn.secondaryInterfaces[iface.Name]; ok {
+			continue
+		}
+
+		if nextTableID > secondaryRouteTableIDEnd {
+			return errors.Errorf("exhausted secondary routing table ID range [%d, %d]",
+				secondaryRouteTableIDStart, secondaryRouteTableIDEnd)
+		}
+
+		ovn.secondaryInterfaces[iface.Name] = &cableRoutingInterface{iface: iface, tableID: nextTableID}
+		nextTableID++
+	}
+
+	return nil
+}
+
+func resolveInterface(selector CableRoutingInterfaceSelector) (*net.Interface, error) {
+	switch {
+	case selector.Name != "":
+		return net.InterfaceByName(selector.Name)
+	case selector.CIDR != "":
+		return interfaceByCIDR(selector.CIDR)
+	case selector.NetworkAttachmentDefinition != "":
+		// Resolving a NetworkAttachmentDefinition reference to its host interface requires
+		// reading the pod's "k8s.v1.cni.cncf.io/network-status" annotation, which is out of
+		// scope for the route agent's host-level Init; for now only Name and CIDR selectors
+		// are resolved directly.
+		return nil, errors.Errorf("resolving cable routing interface by NetworkAttachmentDefinition %q is not yet supported",
+			selector.NetworkAttachmentDefinition)
+	default:
+		return nil, errors.New("cable routing interface selector must set Name, CIDR or NetworkAttachmentDefinition")
+	}
+}
+
+func interfaceByCIDR(rawCIDR string) (*net.Interface, error) {
+	_, wantNet, err := net.ParseCIDR(rawCIDR)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing CIDR %q", rawCIDR)
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing host interfaces")
+	}
+
+	for i := range ifaces {
+		addrs, err := ifaces[i].Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if ok && wantNet.Contains(ipNet.IP) {
+				return &ifaces[i], nil
+			}
+		}
+	}
+
+	return nil, errors.Errorf("no host interface found with an address in %q", rawCIDR)
+}
@@ -0,0 +1,75 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovn
+
+import "testing"
+
+func TestResolveSecondaryCableRoutingInterfacesSkipsUnsupportedSelector(t *testing.T) {
+	t.Parallel()
+
+	ovn := &Handler{secondaryInterfaces: map[string]*cableRoutingInterface{}}
+	ovn.CableRoutingInterfaces = []CableRoutingInterfaceSelector{
+		{NetworkAttachmentDefinition: "default/net1"},
+		{Name: "lo"},
+	}
+
+	if err := ovn.resolveSecondaryCableRoutingInterfaces(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(ovn.secondaryInterfaces) != 1 {
+		t.Fatalf("expected the unsupported selector to be skipped and only 1 interface resolved, got %d",
+			len(ovn.secondaryInterfaces))
+	}
+
+	resolved, ok := ovn.secondaryInterfaces["lo"]
+	if !ok {
+		t.Fatalf("expected %q to be resolved", "lo")
+	}
+
+	if resolved.tableID != secondaryRouteTableIDStart {
+		t.Fatalf("expected table ID %d, got %d", secondaryRouteTableIDStart, resolved.tableID)
+	}
+}
+
+func TestResolveInterfaceRejectsUnsupportedNetworkAttachmentDefinition(t *testing.T) {
+	t.Parallel()
+
+	_, err := resolveInterface(CableRoutingInterfaceSelector{NetworkAttachmentDefinition: "default/net1"})
+	if err == nil {
+		t.Fatal("expected an error for an unsupported NetworkAttachmentDefinition selector")
+	}
+}
+
+func TestInterfaceByCIDR(t *testing.T) {
+	t.Parallel()
+
+	iface, err := interfaceByCIDR("127.0.0.0/8")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if iface.Name != "lo" {
+		t.Fatalf("expected the loopback interface, got %q", iface.Name)
+	}
+
+	if _, err := interfaceByCIDR("203.0.113.0/24"); err == nil {
+		t.Fatal("expected an error when no interface has an address in the given CIDR")
+	}
+}
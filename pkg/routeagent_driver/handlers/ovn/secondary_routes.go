@@ -0,0 +1,123 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+
+Copyright Contributors to the Submariner project.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ovn
+
+import (
+	"net"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
+)
+
+// reconcileSecondaryInterfaceRoutes installs, for every resolved secondary cable routing
+// interface, an ip rule directing traffic to subnet into that interface's dedicated routing
+// table, and a route in that table sending it out the interface. The ip rules match on
+// destination only, not on the source pod's network: with more than one secondary interface,
+// the kernel uses whichever of the rules for subnet it evaluates first, so traffic to subnet from
+// every pod leaves through that one interface. staleSubnets are withdrawn instead: they're
+// no longer covered by any remaining endpoint, so there's nothing left to route. It's a no-op
+// when no secondary interfaces were resolved.
+func (ovn *Handler) reconcileSecondaryInterfaceRoutes(subnets, staleSubnets []string) error {
+	for _, secondary := range ovn.secondaryInterfaces {
+		for _, subnet := range staleSubnets {
+			if err := withdrawSecondaryInterfaceRoute(secondary, subnet); err != nil {
+				return err
+			}
+		}
+
+		for _, subnet := range subnets {
+			if err := installSecondaryInterfaceRoute(secondary, subnet); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func installSecondaryInterfaceRoute(secondary *cableRoutingInterface, subnet string) error {
+	_, dst, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return errors.Wrapf(err, "error parsing subnet %q for secondary interface route", subnet)
+	}
+
+	rule := netlink.NewRule()
+	rule.Dst = dst
+	rule.Table = secondary.tableID
+
+	if err := netlink.RuleAdd(rule); err != nil && !os.IsExist(err) {
+		return errors.Wrapf(err, "error adding ip rule for subnet %q via interface %q", subnet, secondary.iface.Name)
+	}
+
+	route := &netlink.Route{LinkIndex: secondary.iface.Index, Dst: dst, Table: secondary.tableID}
+
+	if err := netlink.RouteReplace(route); err != nil {
+		return errors.Wrapf(err, "error adding route for subnet %q via interface %q", subnet, secondary.iface.Name)
+	}
+
+	return nil
+}
+
+// cleanupSecondaryInterfaceRules removes every ip rule pointing at secondary's routing table, for
+// both address families, so Stop(uninstall) leaves no dangling rules behind once the table itself
+// has been flushed.
+func cleanupSecondaryInterfaceRules(secondary *cableRoutingInterface) error {
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		rules, err := netlink.RuleList(family)
+		if err != nil {
+			return errors.Wrapf(err, "error listing rules for family %d", family)
+		}
+
+		for i := range rules {
+			if rules[i].Table != secondary.tableID {
+				continue
+			}
+
+			if err := netlink.RuleDel(&rules[i]); err != nil {
+				return errors.Wrapf(err, "error deleting the rule %v", rules[i])
+			}
+		}
+	}
+
+	return nil
+}
+
+func withdrawSecondaryInterfaceRoute(secondary *cableRoutingInterface, subnet string) error {
+	_, dst, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return errors.Wrapf(err, "error parsing subnet %q for secondary interface route", subnet)
+	}
+
+	rule := netlink.NewRule()
+	rule.Dst = dst
+	rule.Table = secondary.tableID
+
+	if err := netlink.RuleDel(rule); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error removing ip rule for subnet %q via interface %q", subnet, secondary.iface.Name)
+	}
+
+	route := &netlink.Route{LinkIndex: secondary.iface.Index, Dst: dst, Table: secondary.tableID}
+
+	if err := netlink.RouteDel(route); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error removing route for subnet %q via interface %q", subnet, secondary.iface.Name)
+	}
+
+	return nil
+}
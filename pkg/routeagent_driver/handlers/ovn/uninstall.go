@@ -48,61 +48,56 @@ func (ovn *Handler) Stop(uninstall bool) error {
 		klog.Errorf("Error cleaning the routes %v", err)
 	}
 
-	err = ovn.netlink.FlushRouteTable(constants.RouteAgentInterClusterNetworkTableID)
+	err = ovn.netLink.FlushRouteTable(constants.RouteAgentInterClusterNetworkTableID)
 	if err != nil {
 		klog.Errorf("Flushing routing table %d returned error: %v",
 			constants.RouteAgentInterClusterNetworkTableID, err)
 	}
 
-	err = ovn.netlink.FlushRouteTable(constants.RouteAgentHostNetworkTableID)
+	err = ovn.netLink.FlushRouteTable(constants.RouteAgentHostNetworkTableID)
 	if err != nil {
 		klog.Errorf("Flushing routing table %d returned error: %v",
 			constants.RouteAgentHostNetworkTableID, err)
 	}
 
-	ovn.flushAndDeleteIPTableChains(constants.FilterTable, constants.ForwardChain, forwardingSubmarinerFWDChain)
-	ovn.flushAndDeleteIPTableChains(constants.NATTable, constants.PostRoutingChain, constants.SmPostRoutingChain)
+	for name, secondary := range ovn.secondaryInterfaces {
+		if err := ovn.netLink.FlushRouteTable(secondary.tableID); err != nil {
+			klog.Errorf("Flushing routing table %d for secondary interface %q returned error: %v",
+				secondary.tableID, name, err)
+		}
+
+		if err := cleanupSecondaryInterfaceRules(secondary); err != nil {
+			klog.Errorf("Error removing ip rules for secondary interface %q: %v", name, err)
+		}
+	}
+
+	ovn.dataplane.Teardown()
 
 	return nil
 }
 
+// cleanupRoutes removes every policy rule pointing at the Submariner route tables, for both
+// address families. RouteAgentInterClusterNetworkTableID and RouteAgentHostNetworkTableID are
+// single, family-agnostic table IDs by design, not an oversight: a Linux routing table is itself
+// already address-family-scoped (IPv4 and IPv6 routes in the same table ID never collide), so
+// there's nothing to gain from allocating separate v4/v6 table IDs here, only twice the constants
+// to keep in sync with the rest of the route agent.
 func (ovn *Handler) cleanupRoutes() error {
-	rules, err := netlink.RuleList(netlink.FAMILY_V4)
-	if err != nil {
-		return errors.Wrapf(err, "error listing rules")
-	}
+	for _, family := range []int{netlink.FAMILY_V4, netlink.FAMILY_V6} {
+		rules, err := netlink.RuleList(family)
+		if err != nil {
+			return errors.Wrapf(err, "error listing rules for family %d", family)
+		}
 
-	for i := range rules {
-		if rules[i].Table == constants.RouteAgentInterClusterNetworkTableID || rules[i].Table == constants.RouteAgentHostNetworkTableID {
-			err = netlink.RuleDel(&rules[i])
-			if err != nil {
-				return errors.Wrapf(err, "error deleting the rule %v", rules[i])
+		for i := range rules {
+			if rules[i].Table == constants.RouteAgentInterClusterNetworkTableID || rules[i].Table == constants.RouteAgentHostNetworkTableID {
+				err = netlink.RuleDel(&rules[i])
+				if err != nil {
+					return errors.Wrapf(err, "error deleting the rule %v", rules[i])
+				}
 			}
 		}
 	}
 
 	return nil
 }
-
-func (ovn *Handler) flushAndDeleteIPTableChains(table, tableChain, submarinerChain string) {
-	klog.Infof("Flushing iptable entries in %q chain of %q table", submarinerChain, table)
-
-	if err := ovn.ipt.ClearChain(table, submarinerChain); err != nil {
-		klog.Errorf("Error flushing iptables chain %q of %q table: %v", submarinerChain,
-			table, err)
-	}
-
-	klog.Infof("Deleting iptable entry in %q chain of %q table", tableChain, table)
-
-	ruleSpec := []string{"-j", submarinerChain}
-	if err := ovn.ipt.Delete(table, tableChain, ruleSpec...); err != nil {
-		klog.Errorf("Error deleting iptables rule from %q chain: %v", tableChain, err)
-	}
-
-	klog.Infof("Deleting iptable %q chain of %q table", submarinerChain, table)
-
-	if err := ovn.ipt.DeleteChain(table, submarinerChain); err != nil {
-		klog.Errorf("Error deleting iptable chain %q of table %q: %v", submarinerChain,
-			table, err)
-	}
-}
\ No newline at end of file